@@ -0,0 +1,143 @@
+// Package graphite implements a push bridge that periodically gathers
+// metrics from a Prometheus registry and forwards them to a Graphite
+// carbon server using the plaintext line protocol. It exists for
+// environments that already run Graphite/Carbon and don't want to deploy
+// a Prometheus server just to monitor their Neato robots.
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// ErrorHandling controls what Run does when a push to the Graphite
+// endpoint fails.
+type ErrorHandling int
+
+const (
+	// ContinueOnError logs the failure and keeps running until the next tick.
+	ContinueOnError ErrorHandling = iota
+	// AbortOnError stops Run and returns the error to the caller.
+	AbortOnError
+)
+
+// Bridge periodically gathers samples from a prometheus.Gatherer and
+// pushes them to a Graphite carbon server in the plaintext line protocol.
+type Bridge struct {
+	Gatherer      prometheus.Gatherer
+	Address       string
+	Prefix        string
+	Interval      time.Duration
+	Timeout       time.Duration
+	ErrorHandling ErrorHandling
+}
+
+// NewBridge returns a Bridge that gathers from g and pushes to address
+// every interval, using prefix as the Graphite metric path prefix.
+func NewBridge(g prometheus.Gatherer, address, prefix string, interval, timeout time.Duration, errorHandling ErrorHandling) *Bridge {
+	return &Bridge{
+		Gatherer:      g,
+		Address:       address,
+		Prefix:        prefix,
+		Interval:      interval,
+		Timeout:       timeout,
+		ErrorHandling: errorHandling,
+	}
+}
+
+// Run gathers and pushes metrics to Graphite on every tick of b.Interval,
+// until ctx is cancelled. Depending on b.ErrorHandling, a push error either
+// aborts Run (returning the error) or is logged and ignored.
+func (b *Bridge) Run(ctx context.Context) error {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.push(); err != nil {
+				if b.ErrorHandling == AbortOnError {
+					return err
+				}
+				slog.Error("graphite: failed to push metrics", "address", b.Address, "error", err)
+			}
+		}
+	}
+}
+
+func (b *Bridge) push() error {
+	mfs, err := b.Gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	conn, err := net.DialTimeout("tcp", b.Address, b.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to graphite at '%s': %w", b.Address, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(b.Timeout)); err != nil {
+		return fmt.Errorf("failed to set deadline on graphite connection: %w", err)
+	}
+
+	w := bufio.NewWriter(conn)
+	now := time.Now().UnixMilli() / 1000
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			line, ok := lineForMetric(b.Prefix, mf.GetName(), m, now)
+			if !ok {
+				continue
+			}
+			if _, err := w.WriteString(line); err != nil {
+				return fmt.Errorf("failed to write metric to graphite: %w", err)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// lineForMetric renders a single Prometheus metric sample as one Graphite
+// plaintext line. It returns ok=false for metric types the bridge doesn't
+// support, such as histograms and summaries.
+func lineForMetric(prefix, name string, m *dto.Metric, timestamp int64) (string, bool) {
+	var value float64
+	switch {
+	case m.Gauge != nil:
+		value = m.Gauge.GetValue()
+	case m.Counter != nil:
+		value = m.Counter.GetValue()
+	case m.Untyped != nil:
+		value = m.Untyped.GetValue()
+	default:
+		return "", false
+	}
+
+	path := prefix + "." + sanitize(name)
+	for _, lp := range m.Label {
+		path += fmt.Sprintf(";%s=%s", sanitize(lp.GetName()), sanitize(lp.GetValue()))
+	}
+	return fmt.Sprintf("%s %v %d\n", path, value, timestamp), true
+}
+
+// sanitize replaces characters that are invalid in Graphite metric paths
+// and tag values with underscores.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
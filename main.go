@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,99 +19,290 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/insomniacslk/neato"
+	"github.com/insomniacslk/prometheus-neato-exporter/internal/graphite"
 )
 
 var (
-	flagPath     = pflag.String("p", "/metrics", "HTTP path where to expose metrics to")
-	flagListen   = pflag.StringP("listen-address", "l", ":9110", "Address to listen to")
-	flagToken    = pflag.StringP("token", "t", "", "Authorization token")
-	flagBots     = pflag.StringP("bots", "b", "", "Comma-separated list of bot numbers, e.g. \"1,3\". Use 0 or leave it empty to use all bots. Bot numbering start at 1")
-	flagInterval = pflag.DurationP("interval", "i", 1*time.Minute, "Interval between sensor readings, expressed as a Go duration string")
+	flagPath          = pflag.String("p", "/metrics", "HTTP path where to expose the exporter's own metrics to")
+	flagProbePath     = pflag.String("probe-path", "/probe", "HTTP path for probing a Neato account, e.g. \"/probe?token=...&bot=1,3\"")
+	flagListen        = pflag.StringP("listen-address", "l", ":9110", "Address to listen to")
+	flagConcurrency   = pflag.IntP("concurrency", "c", 4, "Maximum number of robots to query concurrently during a scrape")
+	flagCacheTTL      = pflag.Duration("cache-ttl", 15*time.Second, "How long to keep serving a scrape's results before querying the Neato API again")
+	flagProbeCacheTTL = pflag.Duration("probe-cache-ttl", 5*time.Minute, "How long to cache a token's robot list before re-enumerating it from the Neato API")
+
+	flagGraphiteAddress      = pflag.String("graphite-address", "", "Graphite carbon TCP address to push metrics to, e.g. \"graphite.example.com:2003\". Leave empty to disable the Graphite bridge")
+	flagGraphitePrefix       = pflag.String("graphite-prefix", "neato", "Prefix prepended to every metric pushed to Graphite")
+	flagGraphiteInterval     = pflag.Duration("graphite-interval", 15*time.Second, "Interval between pushes to Graphite")
+	flagGraphiteTimeout      = pflag.Duration("graphite-timeout", 5*time.Second, "Timeout for connecting to and writing to the Graphite carbon endpoint")
+	flagGraphiteAbortOnError = pflag.Bool("graphite-abort-on-error", false, "Exit the exporter if a push to Graphite fails, instead of logging the error and retrying on the next interval")
+
+	flagLogLevel  = pflag.String("log-level", "info", "Minimum log level to emit: debug, info, warn, or error")
+	flagLogFormat = pflag.String("log-format", "logfmt", "Log output format: logfmt or json")
 )
 
+const neatoEndpoint = "https://beehive.neatocloud.com"
+
 var robotAttrs = []string{"name", "serial", "model", "firmware", "mac"}
 
-func makeGauge(name, help string) *prometheus.GaugeVec {
-	return prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "neato_" + name,
-			Help: "Neato - " + help,
-		},
-		robotAttrs,
+var (
+	batteryDesc = prometheus.NewDesc(
+		"neato_battery",
+		"Neato - battery level (percentage)",
+		robotAttrs, nil,
+	)
+	areaDesc = prometheus.NewDesc(
+		"neato_area",
+		"Neato - cleaned area (square meters)",
+		robotAttrs, nil,
+	)
+	stateDesc = prometheus.NewDesc(
+		"neato_state",
+		"Neato - robot state",
+		append(append([]string{}, robotAttrs...), "error", "alert", "state", "action", "category", "navigation_mode", "is_charging", "is_docked", "is_schedule_enabled", "dock_has_been_seen", "charge"),
+		nil,
+	)
+	upDesc = prometheus.NewDesc(
+		"neato_up",
+		"Neato - 1 if the last scrape of this robot succeeded, 0 otherwise",
+		robotAttrs, nil,
+	)
+	scrapeDurationDesc = prometheus.NewDesc(
+		"neato_scrape_duration_seconds",
+		"Neato - time it took to fetch this robot's data from the Neato API",
+		robotAttrs, nil,
+	)
+	scrapeErrorsDesc = prometheus.NewDesc(
+		"neato_scrape_errors_total",
+		"Neato - number of failed scrapes for this robot since the exporter started",
+		robotAttrs, nil,
 	)
+)
+
+// robotSample holds everything collected about one robot during a single
+// scrape.
+type robotSample struct {
+	name, serial, model, firmware, mac string
+
+	up       bool
+	duration float64
+
+	hasBattery bool
+	battery    float64
+
+	hasArea bool
+	area    float64
+
+	hasState    bool
+	stateLabels []string
+
+	failed bool
 }
 
+// neatoCollector is a prometheus.Collector that queries the Neato API for
+// a fixed set of robots on every scrape, instead of relying on a
+// background polling loop. Results are cached for cacheTTL so that a
+// burst of scrapes doesn't hammer the (rate-limited) Neato cloud API.
+type neatoCollector struct {
+	robots      []*neato.Robot
+	concurrency int
+	cacheTTL    time.Duration
+
+	mu         sync.Mutex
+	samples    []robotSample
+	lastScrape time.Time
+}
+
+func newNeatoCollector(robots []*neato.Robot, concurrency int, cacheTTL time.Duration) *neatoCollector {
+	return &neatoCollector{
+		robots:      robots,
+		concurrency: concurrency,
+		cacheTTL:    cacheTTL,
+	}
+}
+
+// scrapeErrorCounts tracks neato_scrape_errors_total per robot serial,
+// independently of any particular neatoCollector instance, so the counter
+// keeps accumulating across the collector churn done by collectorForTarget
+// instead of resetting whenever a target's collector is rebuilt.
 var (
-	batteryGauge = makeGauge("battery", "battery level (percentage)")
-	areaGauge    = makeGauge("area", "cleaned area (square meters)")
-	stateGauge   = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "neato_state",
-			Help: "Neato - robot state",
-		},
-		append(robotAttrs, "error", "alert", "state", "action", "category", "navigation_mode", "is_charging", "is_docked", "is_schedule_enabled", "dock_has_been_seen", "charge"),
-	)
+	scrapeErrorCountsMu sync.Mutex
+	scrapeErrorCounts   = make(map[string]float64)
 )
 
-func collector(robots []*neato.Robot) {
-	for {
-		for _, r := range robots {
-			s, err := r.State()
-			if err != nil {
-				log.Printf("Failed to get state for robot '%s': %v", r.Name, err)
-				time.Sleep(*flagInterval)
-				continue
-			}
-			model := "unknown"
-			if r.Model != nil {
-				model = *r.Model
-			}
-			firmware := "unknown"
-			if r.Firmware != nil {
-				firmware = *r.Firmware
-			}
-			mac := "unknown"
-			if r.MACAddress != nil {
-				mac = *r.MACAddress
-			}
-			batteryGauge.WithLabelValues(r.Name, r.Serial, model, firmware, mac).Set(float64(s.Details.Charge))
+func incrementScrapeErrorCount(serial string) {
+	scrapeErrorCountsMu.Lock()
+	defer scrapeErrorCountsMu.Unlock()
+	scrapeErrorCounts[serial]++
+}
 
-			errStr := "unset"
-			if s.Error != nil {
-				errStr = *s.Error
-			}
-			alert := "unset"
-			if s.Alert != nil {
-				alert = *s.Alert
-			}
-			stateGauge.WithLabelValues(
-				r.Name, r.Serial, model, firmware, mac,
-				errStr, alert, s.State.String(), s.Action.String(),
-				s.Cleaning.Category.String(), s.Cleaning.NavigationMode.String(),
-				strconv.FormatBool(s.Details.IsCharging), strconv.FormatBool(s.Details.IsDocked),
-				strconv.FormatBool(s.Details.IsScheduleEnabled), strconv.FormatBool(s.Details.DockHasBeenSeen),
-				strconv.FormatInt(int64(s.Details.Charge), 10),
-			).Set(1.)
-
-			// get maps
-			maps, err := r.Maps()
-			if err != nil {
-				log.Printf("Failed to get maps for robot '%s' (serial '%s'): %v", r.Name, r.Serial, err)
-			} else {
-				if len(maps) == 0 {
-					log.Printf("No maps found for robot '%s': (serial: '%s')", r.Name, r.Serial)
-				} else {
-					if maps[0].CleanedArea != nil {
-						areaGauge.WithLabelValues(r.Name, r.Serial, model, firmware, mac).Set(float64(*maps[0].CleanedArea))
-					} else {
-						log.Printf("No cleaned area is set for robo '%s' (serial: '%s')", r.Name, r.Serial)
-					}
-				}
+func scrapeErrorCount(serial string) float64 {
+	scrapeErrorCountsMu.Lock()
+	defer scrapeErrorCountsMu.Unlock()
+	return scrapeErrorCounts[serial]
+}
+
+func (c *neatoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- batteryDesc
+	ch <- areaDesc
+	ch <- stateDesc
+	ch <- upDesc
+	ch <- scrapeDurationDesc
+	ch <- scrapeErrorsDesc
+}
+
+func (c *neatoCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	samples := c.samples
+	stale := time.Since(c.lastScrape) >= c.cacheTTL
+	c.mu.Unlock()
+
+	if stale {
+		fresh := c.scrape()
+		for _, s := range fresh {
+			if s.failed {
+				incrementScrapeErrorCount(s.serial)
 			}
 		}
 
-		time.Sleep(*flagInterval)
+		c.mu.Lock()
+		c.samples = fresh
+		c.lastScrape = time.Now()
+		samples = fresh
+		c.mu.Unlock()
 	}
+
+	for _, s := range samples {
+		labels := []string{s.name, s.serial, s.model, s.firmware, s.mac}
+
+		up := 0.
+		if s.up {
+			up = 1.
+		}
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, labels...)
+		ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, s.duration, labels...)
+		ch <- prometheus.MustNewConstMetric(scrapeErrorsDesc, prometheus.CounterValue, scrapeErrorCount(s.serial), labels...)
+
+		if s.hasBattery {
+			ch <- prometheus.MustNewConstMetric(batteryDesc, prometheus.GaugeValue, s.battery, labels...)
+		}
+		if s.hasArea {
+			ch <- prometheus.MustNewConstMetric(areaDesc, prometheus.GaugeValue, s.area, labels...)
+		}
+		if s.hasState {
+			stateLabels := append(append([]string{}, labels...), s.stateLabels...)
+			ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, 1., stateLabels...)
+		}
+	}
+}
+
+// scrape fetches fresh data for every robot, bounded by c.concurrency
+// concurrent requests at a time.
+func (c *neatoCollector) scrape() []robotSample {
+	start := time.Now()
+	samples := make([]robotSample, len(c.robots))
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+
+	for i, r := range c.robots {
+		wg.Add(1)
+		go func(i int, r *neato.Robot) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			samples[i] = fetchRobot(r)
+		}(i, r)
+	}
+	wg.Wait()
+
+	slog.Info("neato scrape complete", "robots", len(samples), "duration_seconds", time.Since(start).Seconds())
+	return samples
+}
+
+// httpStatusPattern extracts the status code from the error strings the
+// neato client returns for unexpected HTTP responses, e.g. "expected HTTP
+// 2xx/3xx, got 404 Not Found". The client doesn't expose a typed error
+// carrying the status code, only this message, so scraping it is the best
+// available signal.
+var httpStatusPattern = regexp.MustCompile(`got (\d{3})`)
+
+// logRobotError logs a robot request failure at error level, including the
+// HTTP status code of the failed request when it can be recovered from the
+// error message.
+func logRobotError(msg string, r *neato.Robot, err error) {
+	args := []any{"robot", r.Name, "serial", r.Serial, "error", err}
+	if m := httpStatusPattern.FindStringSubmatch(err.Error()); m != nil {
+		if code, convErr := strconv.Atoi(m[1]); convErr == nil {
+			args = append(args, "status_code", code)
+		}
+	}
+	slog.Error(msg, args...)
+}
+
+// fetchRobot queries the Neato API for a single robot's state and maps.
+func fetchRobot(r *neato.Robot) robotSample {
+	start := time.Now()
+
+	s := robotSample{
+		name:     r.Name,
+		serial:   r.Serial,
+		model:    "unknown",
+		firmware: "unknown",
+		mac:      "unknown",
+	}
+	if r.Model != nil {
+		s.model = *r.Model
+	}
+	if r.Firmware != nil {
+		s.firmware = *r.Firmware
+	}
+	if r.MACAddress != nil {
+		s.mac = *r.MACAddress
+	}
+
+	state, err := r.State()
+	if err != nil {
+		logRobotError("failed to get robot state", r, err)
+		s.failed = true
+	} else {
+		s.up = true
+		s.hasBattery = true
+		s.battery = float64(state.Details.Charge)
+
+		errStr := "unset"
+		if state.Error != nil {
+			errStr = *state.Error
+		}
+		alert := "unset"
+		if state.Alert != nil {
+			alert = *state.Alert
+		}
+		s.hasState = true
+		s.stateLabels = []string{
+			errStr, alert, state.State.String(), state.Action.String(),
+			state.Cleaning.Category.String(), state.Cleaning.NavigationMode.String(),
+			strconv.FormatBool(state.Details.IsCharging), strconv.FormatBool(state.Details.IsDocked),
+			strconv.FormatBool(state.Details.IsScheduleEnabled), strconv.FormatBool(state.Details.DockHasBeenSeen),
+			strconv.FormatInt(int64(state.Details.Charge), 10),
+		}
+	}
+
+	maps, err := r.Maps()
+	if err != nil {
+		logRobotError("failed to get robot maps", r, err)
+		s.failed = true
+	} else if len(maps) == 0 {
+		slog.Debug("no maps found for robot", "robot", r.Name, "serial", r.Serial)
+	} else if maps[0].CleanedArea != nil {
+		s.hasArea = true
+		s.area = float64(*maps[0].CleanedArea)
+	} else {
+		slog.Debug("no cleaned area set for robot", "robot", r.Name, "serial", r.Serial)
+	}
+
+	s.up = !s.failed
+	s.duration = time.Since(start).Seconds()
+	slog.Debug("fetched robot", "robot", r.Name, "serial", r.Serial, "duration_seconds", s.duration, "up", s.up)
+	return s
 }
 
 func getBots(s string) ([]int, error) {
@@ -146,62 +341,235 @@ func getBots(s string) ([]int, error) {
 	return bots, nil
 }
 
-func main() {
-	pflag.Parse()
-
-	if *flagToken == "" {
-		log.Fatalf("Empty authorization token")
+// selectBots returns the subset of allRobots referenced by bots (1-indexed),
+// or allRobots itself when bots is nil.
+func selectBots(allRobots []*neato.Robot, bots []int) ([]*neato.Robot, error) {
+	if bots == nil {
+		// we want all the bots
+		return allRobots, nil
+	}
+	robots := make([]*neato.Robot, 0, len(bots))
+	for _, n := range bots {
+		if n > len(allRobots) {
+			return nil, fmt.Errorf("robot number %d out of bounds, there are %d robots in total", n, len(allRobots))
+		}
+		robots = append(robots, allRobots[n-1])
 	}
+	return robots, nil
+}
 
-	bots, err := getBots(*flagBots)
-	if err != nil {
-		log.Fatalf("Failed to parse bot indexes: %v", err)
+// robotsCacheEntry holds the result of enumerating a token's robots, along
+// with when that result stops being valid.
+type robotsCacheEntry struct {
+	robots    []*neato.Robot
+	expiresAt time.Time
+}
+
+var (
+	robotsCacheMu sync.Mutex
+	robotsCache   = make(map[string]robotsCacheEntry)
+)
+
+// robotsForToken resolves the robots belonging to the account identified by
+// token, reusing a cached result if it's younger than *flagProbeCacheTTL.
+func robotsForToken(token string) ([]*neato.Robot, error) {
+	robotsCacheMu.Lock()
+	if entry, ok := robotsCache[token]; ok && time.Now().Before(entry.expiresAt) {
+		robotsCacheMu.Unlock()
+		return entry.robots, nil
 	}
+	robotsCacheMu.Unlock()
 
-	endpoint := "https://beehive.neatocloud.com"
 	header := url.Values{}
-	header.Set("Authorization", fmt.Sprintf("Token token=%s", *flagToken))
-	s := neato.NewPasswordSession(endpoint, &header)
+	header.Set("Authorization", fmt.Sprintf("Token token=%s", token))
+	s := neato.NewPasswordSession(neatoEndpoint, &header)
 	acc := neato.NewAccount(s)
 
-	allRobots, err := acc.Robots()
+	robots, err := acc.Robots()
 	if err != nil {
-		log.Fatalf("Failed to get robots: %v", err)
+		return nil, fmt.Errorf("failed to get robots: %w", err)
 	}
-	if len(allRobots) == 0 {
-		log.Fatalf("No bots found")
+	if len(robots) == 0 {
+		return nil, fmt.Errorf("no bots found")
 	}
+	slog.Info("discovered robots", "count", len(robots))
+
+	robotsCacheMu.Lock()
+	robotsCache[token] = robotsCacheEntry{robots: robots, expiresAt: time.Now().Add(*flagProbeCacheTTL)}
+	robotsCacheMu.Unlock()
 
-	robots := make([]*neato.Robot, 0)
+	return robots, nil
+}
+
+// collectorCacheEntry holds a neatoCollector built for a specific
+// (token, bot selection) pair.
+type collectorCacheEntry struct {
+	collector *neatoCollector
+	expiresAt time.Time
+}
+
+var (
+	collectorsCacheMu sync.Mutex
+	collectorsCache   = make(map[string]collectorCacheEntry)
+)
+
+// graphiteRegistry collects the neatoCollector for every /probe target ever
+// served, so the graphite bridge (which gathers from it in addition to
+// prometheus.DefaultGatherer) can still push per-robot metrics to Graphite
+// even though /probe itself serves each request from its own throwaway
+// registry.
+var graphiteRegistry = prometheus.NewRegistry()
+
+// collectorKey builds a stable cache key for a (token, bots) pair. bots is
+// nil (all bots) or a list of bot numbers in any order; a sorted copy is
+// used for the key.
+func collectorKey(token string, bots []int) string {
 	if bots == nil {
-		// we want all the bots
-		robots = allRobots
-	} else {
-		// we only want a subset of bots.
-		for _, n := range bots {
-			if n > len(allRobots) {
-				log.Fatalf("Robot number %d out of bounds, there are %d robots in total", n, len(allRobots))
-			}
-			robots = append(robots, allRobots[n-1])
-		}
+		return token + "|all"
+	}
+	sorted := append([]int(nil), bots...)
+	sort.Ints(sorted)
+	parts := make([]string, len(sorted))
+	for i, n := range sorted {
+		parts[i] = strconv.Itoa(n)
 	}
+	return token + "|" + strings.Join(parts, ",")
+}
+
+// collectorForTarget returns the neatoCollector for a (token, bots) pair,
+// reusing it across /probe requests for *flagProbeCacheTTL instead of
+// building a fresh one (with a cold scrape cache) on every scrape. Each collector is also kept registered in
+// graphiteRegistry for the lifetime of its cache entry, so the graphite
+// bridge keeps seeing every target that has been probed at least once.
+func collectorForTarget(token string, bots []int, robots []*neato.Robot) *neatoCollector {
+	key := collectorKey(token, bots)
 
-	// register all gauges
-	if err := prometheus.Register(batteryGauge); err != nil {
-		log.Fatalf("Failed to register Neato battery gauge: %v", err)
+	collectorsCacheMu.Lock()
+	defer collectorsCacheMu.Unlock()
+
+	if entry, ok := collectorsCache[key]; ok && time.Now().Before(entry.expiresAt) {
+		return entry.collector
 	}
-	if err := prometheus.Register(areaGauge); err != nil {
-		log.Fatalf("Failed to register Neato area gauge: %v", err)
+
+	if entry, ok := collectorsCache[key]; ok {
+		graphiteRegistry.Unregister(entry.collector)
 	}
-	if err := prometheus.Register(stateGauge); err != nil {
-		log.Fatalf("Failed to register Neato state gauge: %v", err)
+
+	c := newNeatoCollector(robots, *flagConcurrency, *flagCacheTTL)
+	graphiteRegistry.MustRegister(c)
+	collectorsCache[key] = collectorCacheEntry{collector: c, expiresAt: time.Now().Add(*flagProbeCacheTTL)}
+	return c
+}
+
+// probeHandler resolves the robots for the account identified by the
+// "token" query parameter, optionally narrowed down by the "bot" query
+// parameter, and serves their metrics from a per-request registry. This
+// follows the same pattern as the blackbox and snmp exporters, letting one
+// exporter process serve multiple Neato accounts driven by Prometheus
+// relabeling.
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "missing 'token' parameter", http.StatusBadRequest)
+		return
 	}
 
-	// start collector
-	go collector(robots)
+	allRobots, err := robotsForToken(token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to resolve robots: %v", err), http.StatusBadGateway)
+		return
+	}
 
-	server := http.Server{Addr: *flagListen}
+	bots, err := getBots(r.URL.Query().Get("bot"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid 'bot' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	robots, err := selectBots(allRobots, bots)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid 'bot' parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectorForTarget(token, bots, robots))
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// parseLogLevel converts a --log-level flag value into a slog.Level.
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level '%s'", s)
+	}
+}
+
+// setupLogging configures the default slog logger from the --log-level and
+// --log-format flags.
+func setupLogging() error {
+	level, err := parseLogLevel(*flagLogLevel)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(*flagLogFormat) {
+	case "logfmt":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format '%s'", *flagLogFormat)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func main() {
+	pflag.Parse()
+
+	if err := setupLogging(); err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+
+	http.HandleFunc(*flagProbePath, probeHandler)
 	http.Handle(*flagPath, promhttp.Handler())
-	log.Printf("Starting server on %s", *flagListen)
-	log.Fatal(server.ListenAndServe())
+
+	if *flagGraphiteAddress != "" {
+		errorHandling := graphite.ContinueOnError
+		if *flagGraphiteAbortOnError {
+			errorHandling = graphite.AbortOnError
+		}
+		bridge := graphite.NewBridge(
+			prometheus.Gatherers{prometheus.DefaultGatherer, graphiteRegistry},
+			*flagGraphiteAddress,
+			*flagGraphitePrefix,
+			*flagGraphiteInterval,
+			*flagGraphiteTimeout,
+			errorHandling,
+		)
+		go func() {
+			if err := bridge.Run(context.Background()); err != nil {
+				slog.Error("graphite bridge exited", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	server := http.Server{Addr: *flagListen}
+	slog.Info("starting server", "address", *flagListen)
+	if err := server.ListenAndServe(); err != nil {
+		slog.Error("server exited", "error", err)
+		os.Exit(1)
+	}
 }